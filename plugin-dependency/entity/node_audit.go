@@ -0,0 +1,72 @@
+package entity
+
+import (
+	"encoding/json"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// NpmAuditResult is the parsed result of `npm audit --json` (and `pnpm
+// audit --json`). npm <7 and pnpm report advisories under Advisories; npm
+// >=7 report them under Vulnerabilities instead (auditReportVersion 2) and
+// never populate Advisories. `yarn audit --json` prints one advisory per
+// line and is not parsed into this type.
+type NpmAuditResult struct {
+	Advisories      map[string]NpmAuditAdvisory      `json:"advisories"`
+	Vulnerabilities map[string]NpmAuditVulnerability `json:"vulnerabilities"`
+}
+
+// NpmAuditAdvisory is a single npm <7 advisory entry.
+type NpmAuditAdvisory struct {
+	ModuleName         string            `json:"module_name"`
+	Severity           string            `json:"severity"`
+	Url                string            `json:"url"`
+	VulnerableVersions string            `json:"vulnerable_versions"`
+	PatchedVersions    string            `json:"patched_versions"`
+	Findings           []NpmAuditFinding `json:"findings"`
+}
+
+type NpmAuditFinding struct {
+	Version string `json:"version"`
+}
+
+// NpmAuditVulnerability is a single npm >=7 vulnerability entry. Via
+// mixes plain advisory-name strings with advisory objects, and
+// FixAvailable mixes a bool with an object describing the fix, so both
+// are decoded on demand from raw JSON.
+type NpmAuditVulnerability struct {
+	Name         string            `json:"name"`
+	Severity     string            `json:"severity"`
+	Range        string            `json:"range"`
+	Via          []json.RawMessage `json:"via"`
+	FixAvailable json.RawMessage   `json:"fixAvailable"`
+}
+
+// NpmAuditVulnerabilityVia is the advisory-object form of a
+// NpmAuditVulnerability.Via entry (the other form is a plain advisory
+// name string, carrying no extra detail).
+type NpmAuditVulnerabilityVia struct {
+	Title string `json:"title"`
+	Url   string `json:"url"`
+	Range string `json:"range"`
+}
+
+// NpmAuditFixAvailable is the object form of
+// NpmAuditVulnerability.FixAvailable (the other form is a plain bool).
+type NpmAuditFixAvailable struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// FixParams carries the vulnerable dependencies a user picked to patch via
+// NodeService.Fix.
+type FixParams struct {
+	Cmd    string             `json:"cmd"`
+	TaskId primitive.ObjectID `json:"task_id"`
+	Fixes  []FixTarget        `json:"fixes"`
+}
+
+// FixTarget is a single package to upgrade to its patched version.
+type FixTarget struct {
+	Name           string `json:"name"`
+	PatchedVersion string `json:"patched_version"`
+}
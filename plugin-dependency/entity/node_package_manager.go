@@ -0,0 +1,37 @@
+package entity
+
+// YarnListResult is the parsed result of `yarn global list --json`.
+//
+// Yarn prints one JSON object per line on stdout; the line carrying the
+// dependency tree has Type "tree" and its Data.Trees holds one entry per
+// top-level package in the form "name@version".
+type YarnListResult struct {
+	Type string             `json:"type"`
+	Data YarnListResultData `json:"data"`
+}
+
+type YarnListResultData struct {
+	Type  string         `json:"type"`
+	Trees []YarnListTree `json:"trees"`
+}
+
+type YarnListTree struct {
+	Name     string         `json:"name"`
+	Children []YarnListTree `json:"children,omitempty"`
+}
+
+// PnpmListResult is the parsed result of `pnpm list -g --json`.
+//
+// pnpm always returns a JSON array, even for the global install, with a
+// single element describing the global "project".
+type PnpmListResult []PnpmListResultItem
+
+type PnpmListResultItem struct {
+	Path         string                        `json:"path"`
+	Dependencies map[string]PnpmListDependency `json:"dependencies"`
+}
+
+type PnpmListDependency struct {
+	Version string `json:"version"`
+	From    string `json:"from"`
+}
@@ -0,0 +1,23 @@
+package entity
+
+// NpmRegistrySearchResult is the response shape of a registry's
+// `/-/v1/search` endpoint, used as a fallback when npms.io is unreachable
+// or the query targets a configured scope.
+type NpmRegistrySearchResult struct {
+	Total   int                     `json:"total"`
+	Objects []NpmRegistrySearchItem `json:"objects"`
+}
+
+type NpmRegistrySearchItem struct {
+	Package struct {
+		Name    string `json:"name"`
+		Version string `json:"version"`
+	} `json:"package"`
+}
+
+// NpmRegistryPackageResult is the response shape of a registry's
+// `/{package}` endpoint.
+type NpmRegistryPackageResult struct {
+	Name     string            `json:"name"`
+	DistTags map[string]string `json:"dist-tags"`
+}
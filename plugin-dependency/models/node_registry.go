@@ -0,0 +1,26 @@
+package models
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+// NodeRegistry is a configured npm-compatible registry used for searching
+// and installing scoped/private packages, persisted alongside the rest of
+// the plugin config.
+type NodeRegistry struct {
+	Id primitive.ObjectID `json:"_id,omitempty" bson:"_id,omitempty"`
+
+	// Url is the registry base url, e.g. "https://npm.mycorp.com".
+	Url string `json:"url" bson:"url"`
+
+	// Scope restricts this registry to packages under a given scope, e.g.
+	// "@mycorp". Empty means it is used as the default registry.
+	Scope string `json:"scope" bson:"scope"`
+
+	// AuthType is "bearer", "basic" or "" for no auth.
+	AuthType string `json:"auth_type" bson:"auth_type"`
+
+	// Token is the bearer token, or the raw (not base64-encoded)
+	// "user:pass" for basic auth. Callers base64-encode it where the
+	// wire format requires it (the Authorization header, the npmrc
+	// _auth field).
+	Token string `json:"token" bson:"token"`
+}
@@ -0,0 +1,27 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+// NodeRegistryCache is a cached npms.io response, keyed by endpoint plus
+// the request parameters that produced it. ExpireAt is indexed with a
+// MongoDB TTL index (see NodeService.ensureCacheIndex) so expired entries
+// are reaped automatically.
+type NodeRegistryCache struct {
+	Id primitive.ObjectID `json:"_id,omitempty" bson:"_id,omitempty"`
+
+	// Key identifies the request, e.g. "search:<query>:<page>:<size>" or
+	// "detail:<package_name>".
+	Key string `json:"key" bson:"key"`
+
+	// Endpoint is the npms.io endpoint that was called, e.g. "search" or
+	// "package".
+	Endpoint string `json:"endpoint" bson:"endpoint"`
+
+	// Payload is the raw upstream JSON response.
+	Payload []byte `json:"payload" bson:"payload"`
+
+	ExpireAt time.Time `json:"expire_at" bson:"expire_at"`
+}
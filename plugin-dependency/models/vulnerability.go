@@ -0,0 +1,18 @@
+package models
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+// Vulnerability is an advisory reported by a package manager's audit
+// command (e.g. `npm audit`) against a node's global dependencies.
+type Vulnerability struct {
+	Id primitive.ObjectID `json:"_id,omitempty" bson:"_id,omitempty"`
+
+	NodeId primitive.ObjectID `json:"node_id,omitempty" bson:"node_id,omitempty"`
+
+	Package          string `json:"package" bson:"package"`
+	InstalledVersion string `json:"installed_version" bson:"installed_version"`
+	Severity         string `json:"severity" bson:"severity"`
+	AdvisoryUrl      string `json:"advisory_url" bson:"advisory_url"`
+	PatchedRange     string `json:"patched_range" bson:"patched_range"`
+	FixAvailable     bool   `json:"fix_available" bson:"fix_available"`
+}
@@ -27,6 +27,13 @@ func (svc *NodeService) Init() {
 	svc.api.POST("/node/update", svc.update)
 	svc.api.POST("/node/install", svc.install)
 	svc.api.POST("/node/uninstall", svc.uninstall)
+	svc.api.POST("/node/audit", svc.audit)
+	svc.api.POST("/node/audit/fix", svc.fix)
+	svc.api.POST("/node/cache/invalidate", svc.invalidateCache)
+
+	if err := svc.ensureCacheIndex(); err != nil {
+		_ = trace.TraceError(err)
+	}
 }
 
 func (svc *NodeService) GetRepoList(c *gin.Context) {
@@ -40,58 +47,101 @@ func (svc *NodeService) GetRepoList(c *gin.Context) {
 		return
 	}
 
-	// request session
-	reqSession := req.New()
+	// from/size
+	from := (pagination.Page - 1) * pagination.Size
+	size := 20
+
+	// registry to use if the query targets a scope, else the default
+	// (unscoped) registry to fall back to on npms.io failure, or none if
+	// neither is configured
+	registry, scoped := svc.matchRegistryByScope(query)
+	if registry == nil {
+		if registries, err := svc.getRegistries(); err == nil {
+			for i := range registries {
+				if registries[i].Scope == "" {
+					registry = &registries[i]
+					break
+				}
+			}
+		}
+	}
 
-	// set timeout
-	reqSession.SetTimeout(15 * time.Second)
+	var deps []models.Dependency
+	var depNames []string
+	var total int
+	if scoped && registry != nil {
+		// scoped query: go straight to the configured registry
+		d, n, t, err := svc.searchRegistry(*registry, query, from, size)
+		if err != nil {
+			controllers.HandleErrorInternalServerError(c, err)
+			return
+		}
+		deps, depNames, total = d, n, t
+	} else {
+		// cached npms.io response, if any
+		cacheKey := fmt.Sprintf("search:%s:%d:%d", query, pagination.Page, pagination.Size)
+		data, err := svc.fetchWithCache(cacheKey, "search", cacheTtlSearch, func() ([]byte, error) {
+			// request session
+			reqSession := req.New()
 
-	// user agent
-	ua := req.Header{"user-agent": "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_14_6) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/94.0.4606.61 Safari/537.36"}
+			// set timeout
+			reqSession.SetTimeout(15 * time.Second)
 
-	// request url
-	requestUrl := fmt.Sprintf("https://api.npms.io/v2/search?from=%d&q=%s&size=20", (pagination.Page-1)*pagination.Size, url.QueryEscape(query))
+			// user agent
+			ua := req.Header{"user-agent": "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_14_6) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/94.0.4606.61 Safari/537.36"}
 
-	// perform request
-	res, err := reqSession.Get(requestUrl, ua)
-	if err != nil {
-		if res != nil {
-			_, _ = c.Writer.Write(res.Bytes())
-			_ = c.AbortWithError(res.Response().StatusCode, err)
-			return
-		}
-		controllers.HandleErrorInternalServerError(c, err)
-		return
-	}
+			// request url
+			requestUrl := fmt.Sprintf("https://api.npms.io/v2/search?from=%d&q=%s&size=%d", from, url.QueryEscape(query), size)
 
-	// response
-	var npmRes entity.NpmResponseList
-	if err := res.ToJSON(&npmRes); err != nil {
-		controllers.HandleErrorInternalServerError(c, err)
-		return
+			// perform request
+			res, err := reqSession.Get(requestUrl, ua)
+			if err != nil {
+				return nil, err
+			}
+			if res.Response().StatusCode >= 300 {
+				return nil, fmt.Errorf("npms.io search returned status %d", res.Response().StatusCode)
+			}
+			return res.Bytes(), nil
+		})
+		if err != nil {
+			// npms.io unreachable: fall back to a configured registry if any
+			if registry != nil {
+				d, n, t, err := svc.searchRegistry(*registry, query, from, size)
+				if err != nil {
+					controllers.HandleErrorInternalServerError(c, err)
+					return
+				}
+				deps, depNames, total = d, n, t
+			} else {
+				controllers.HandleErrorInternalServerError(c, err)
+				return
+			}
+		} else {
+			// response
+			var npmRes entity.NpmResponseList
+			if err := json.Unmarshal(data, &npmRes); err != nil {
+				controllers.HandleErrorInternalServerError(c, err)
+				return
+			}
+
+			for _, r := range npmRes.Results {
+				d := models.Dependency{
+					Name:          r.Package.Name,
+					LatestVersion: r.Package.Version,
+				}
+				deps = append(deps, d)
+				depNames = append(depNames, d.Name)
+			}
+			total = npmRes.Total
+		}
 	}
 
 	// empty results
-	if npmRes.Total == 0 {
+	if total == 0 {
 		controllers.HandleSuccess(c)
 		return
 	}
 
-	// dependencies
-	var deps []models.Dependency
-	var depNames []string
-	for _, r := range npmRes.Results {
-		d := models.Dependency{
-			Name:          r.Package.Name,
-			LatestVersion: r.Package.Version,
-		}
-		deps = append(deps, d)
-		depNames = append(depNames, d.Name)
-	}
-
-	// total
-	total := npmRes.Total
-
 	// dependencies in db
 	var depsResults []entity.DependencyResult
 	pipelines := mongo2.Pipeline{
@@ -144,62 +194,53 @@ func (svc *NodeService) GetRepoList(c *gin.Context) {
 		}
 	}
 
-	controllers.HandleSuccessWithListData(c, deps, total)
+	// vulnerabilities known for these packages, surfaced alongside Result
+	vulnsMap, err := svc.getVulnerabilitiesByNames(depNames)
+	if err != nil {
+		controllers.HandleErrorInternalServerError(c, err)
+		return
+	}
+	depsWithVulns := make([]dependencyWithVulnerabilities, len(deps))
+	for i, d := range deps {
+		depsWithVulns[i] = dependencyWithVulnerabilities{
+			Dependency:      d,
+			Vulnerabilities: vulnsMap[d.Name],
+		}
+	}
+
+	controllers.HandleSuccessWithListData(c, depsWithVulns, total)
 }
 
 func (svc *NodeService) GetDependencies(params entity.UpdateParams) (deps []models.Dependency, err error) {
-	cmd := exec.Command(params.Cmd, "list", "-g", "--json", "--depth", "0")
-	data, err := cmd.Output()
+	pm, err := getPackageManager(params.Cmd)
 	if err != nil {
 		return nil, err
 	}
-	var res entity.NpmListResult
-	if err := json.Unmarshal(data, &res); err != nil {
+	cmd := exec.Command(pm.Bin(), pm.ListArgs()...)
+	data, err := cmd.Output()
+	if err != nil {
 		return nil, err
 	}
-	for name, p := range res.Dependencies {
-		d := models.Dependency{
-			Name:    name,
-			Version: p.Version,
-		}
-		d.Type = constants.DependencyTypeNode
-		deps = append(deps, d)
-	}
-	return deps, nil
+	return pm.ParseList(data)
 }
 
 func (svc *NodeService) InstallDependencies(params entity.InstallParams) (err error) {
-	// arguments
-	var args []string
-
-	// install
-	args = append(args, "install")
-
-	// global
-	args = append(args, "-g")
-
-	// proxy
-	if params.Proxy != "" {
-		args = append(args, "--registry")
-		args = append(args, params.Proxy)
+	// package manager
+	pm, err := getPackageManager(params.Cmd)
+	if err != nil {
+		return trace.TraceError(err)
 	}
 
-	if params.UseConfig {
-		// use config
-	} else {
-		// dependency names
-		for _, depName := range params.Names {
-			// upgrade
-			if params.Upgrade {
-				depName = depName + "@latest"
-			}
+	// command
+	cmd := exec.Command(pm.Bin(), pm.InstallArgs(params)...)
 
-			args = append(args, depName)
-		}
+	// registry auth: a temp .npmrc keeps tokens off argv (and therefore
+	// out of the command logs)
+	cleanup, err := svc.configureRegistryAuth(cmd)
+	if err != nil {
+		return trace.TraceError(err)
 	}
-
-	// command
-	cmd := exec.Command(params.Cmd, args...)
+	defer cleanup()
 
 	// logging
 	svc.parent._configureLogging(params.TaskId, cmd)
@@ -218,20 +259,22 @@ func (svc *NodeService) InstallDependencies(params entity.InstallParams) (err er
 }
 
 func (svc *NodeService) UninstallDependencies(params entity.UninstallParams) (err error) {
-	// arguments
-	var args []string
-
-	// uninstall
-	args = append(args, "uninstall")
-	args = append(args, "-g")
-
-	// dependency names
-	for _, depName := range params.Names {
-		args = append(args, depName)
+	// package manager
+	pm, err := getPackageManager(params.Cmd)
+	if err != nil {
+		return trace.TraceError(err)
 	}
 
 	// command
-	cmd := exec.Command(params.Cmd, args...)
+	cmd := exec.Command(pm.Bin(), pm.UninstallArgs(params)...)
+
+	// registry auth: a temp .npmrc keeps tokens off argv (and therefore
+	// out of the command logs)
+	cleanup, err := svc.configureRegistryAuth(cmd)
+	if err != nil {
+		return trace.TraceError(err)
+	}
+	defer cleanup()
 
 	// logging
 	svc.parent._configureLogging(params.TaskId, cmd)
@@ -250,27 +293,46 @@ func (svc *NodeService) UninstallDependencies(params entity.UninstallParams) (er
 }
 
 func (svc *NodeService) GetLatestVersion(dep models.Dependency) (v string, err error) {
-	// not exists in cache, request from pypi
-	reqSession := req.New()
+	// scoped packages are resolved against their configured registry
+	if registry, ok := svc.matchRegistryByScope(dep.Name); ok {
+		res, err := newRegistryClient(*registry).getPackage(dep.Name)
+		if err != nil {
+			return "", trace.TraceError(err)
+		}
+		return res.DistTags["latest"], nil
+	}
+
+	// cached npms.io response, if any
+	cacheKey := fmt.Sprintf("detail:%s", dep.Name)
+	data, err := svc.fetchWithCache(cacheKey, "package", cacheTtlDetail, func() ([]byte, error) {
+		reqSession := req.New()
 
-	// set timeout
-	reqSession.SetTimeout(60 * time.Second)
+		// set timeout
+		reqSession.SetTimeout(60 * time.Second)
 
-	// user agent
-	ua := req.Header{"user-agent": "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_14_6) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/94.0.4606.61 Safari/537.36"}
+		// user agent
+		ua := req.Header{"user-agent": "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_14_6) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/94.0.4606.61 Safari/537.36"}
 
-	// request url
-	requestUrl := fmt.Sprintf("https://api.npms.io/v2/package/%s", dep.Name)
+		// request url
+		requestUrl := fmt.Sprintf("https://api.npms.io/v2/package/%s", dep.Name)
 
-	// perform request
-	res, err := reqSession.Get(requestUrl, ua)
+		// perform request
+		res, err := reqSession.Get(requestUrl, ua)
+		if err != nil {
+			return nil, err
+		}
+		if res.Response().StatusCode >= 300 {
+			return nil, fmt.Errorf("npms.io package lookup returned status %d", res.Response().StatusCode)
+		}
+		return res.Bytes(), nil
+	})
 	if err != nil {
 		return "", trace.TraceError(err)
 	}
 
 	// response
 	var npmRes entity.NpmResponseDetail
-	if err := res.ToJSON(&npmRes); err != nil {
+	if err := json.Unmarshal(data, &npmRes); err != nil {
 		return "", trace.TraceError(err)
 	}
 
@@ -0,0 +1,170 @@
+package services
+
+import (
+	"github.com/crawlab-team/crawlab-core/controllers"
+	"github.com/crawlab-team/go-trace"
+	"github.com/crawlab-team/plugin-dependency/entity"
+	"github.com/crawlab-team/plugin-dependency/models"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"os/exec"
+)
+
+// Audit runs the configured package manager's audit command against the
+// node's global install and returns the parsed advisories.
+func (svc *NodeService) Audit(params entity.UpdateParams) (vulns []models.Vulnerability, err error) {
+	pm, err := getPackageManager(params.Cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	deps, err := svc.listInstalled(pm)
+	if err != nil {
+		return nil, err
+	}
+
+	dir, cleanup, err := pm.PrepareAudit(deps)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+
+	cmd := exec.Command(pm.Bin(), pm.AuditArgs()...)
+	cmd.Dir = dir
+	data, err := cmd.Output()
+	if err != nil {
+		// audit commands exit non-zero when vulnerabilities are found,
+		// but still print the report on stdout
+		if len(data) == 0 {
+			return nil, err
+		}
+	}
+	vulns, err = pm.ParseAudit(data)
+	if err != nil {
+		return nil, err
+	}
+
+	// some audit report schemas (e.g. npm >=7) don't carry the installed
+	// version on the vulnerability itself; fill it in from the global
+	// install list.
+	installedVersions := map[string]string{}
+	for _, d := range deps {
+		installedVersions[d.Name] = d.Version
+	}
+	for i, v := range vulns {
+		if v.InstalledVersion == "" {
+			vulns[i].InstalledVersion = installedVersions[v.Package]
+		}
+	}
+
+	return vulns, nil
+}
+
+// listInstalled lists the node's global install.
+func (svc *NodeService) listInstalled(pm PackageManager) (deps []models.Dependency, err error) {
+	cmd := exec.Command(pm.Bin(), pm.ListArgs()...)
+	data, err := cmd.Output()
+	if err != nil {
+		return nil, err
+	}
+	return pm.ParseList(data)
+}
+
+// Fix upgrades the selected vulnerable dependencies to their patched
+// version, reusing the install task/logging pipeline.
+func (svc *NodeService) Fix(params entity.FixParams) (err error) {
+	var names []string
+	for _, f := range params.Fixes {
+		names = append(names, f.Name+"@"+f.PatchedVersion)
+	}
+	return svc.InstallDependencies(entity.InstallParams{
+		Cmd:    params.Cmd,
+		TaskId: params.TaskId,
+		Names:  names,
+	})
+}
+
+// saveVulnerabilities replaces the stored vulnerabilities for a node with
+// the latest audit results.
+func (svc *NodeService) saveVulnerabilities(nodeId primitive.ObjectID, vulns []models.Vulnerability) (err error) {
+	if _, err := svc.parent.colVulnerability.DeleteAll(bson.M{"node_id": nodeId}); err != nil {
+		return err
+	}
+	for i := range vulns {
+		vulns[i].NodeId = nodeId
+	}
+	if len(vulns) == 0 {
+		return nil
+	}
+	var docs []interface{}
+	for _, v := range vulns {
+		docs = append(docs, v)
+	}
+	_, err = svc.parent.colVulnerability.InsertMany(docs)
+	return err
+}
+
+// getVulnerabilitiesByNames returns the stored vulnerabilities grouped by
+// package name, for surfacing alongside entity.DependencyResult.
+func (svc *NodeService) getVulnerabilitiesByNames(names []string) (res map[string][]models.Vulnerability, err error) {
+	res = map[string][]models.Vulnerability{}
+	if len(names) == 0 {
+		return res, nil
+	}
+	var vulns []models.Vulnerability
+	if err := svc.parent.colVulnerability.Find(bson.M{"package": bson.M{"$in": names}}, nil).All(&vulns); err != nil {
+		return nil, err
+	}
+	for _, v := range vulns {
+		res[v.Package] = append(res[v.Package], v)
+	}
+	return res, nil
+}
+
+// dependencyWithVulnerabilities augments a dependency with any known
+// vulnerabilities, without disturbing the existing entity.DependencyResult
+// shape on models.Dependency.
+type dependencyWithVulnerabilities struct {
+	models.Dependency
+	Vulnerabilities []models.Vulnerability `json:"vulnerabilities,omitempty"`
+}
+
+func (svc *NodeService) audit(c *gin.Context) {
+	var payload struct {
+		NodeId primitive.ObjectID `json:"node_id"`
+		Cmd    string             `json:"cmd"`
+	}
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		controllers.HandleErrorBadRequest(c, err)
+		return
+	}
+
+	vulns, err := svc.Audit(entity.UpdateParams{Cmd: payload.Cmd})
+	if err != nil {
+		controllers.HandleErrorInternalServerError(c, trace.TraceError(err))
+		return
+	}
+
+	if err := svc.saveVulnerabilities(payload.NodeId, vulns); err != nil {
+		controllers.HandleErrorInternalServerError(c, trace.TraceError(err))
+		return
+	}
+
+	controllers.HandleSuccessWithData(c, vulns)
+}
+
+func (svc *NodeService) fix(c *gin.Context) {
+	var params entity.FixParams
+	if err := c.ShouldBindJSON(&params); err != nil {
+		controllers.HandleErrorBadRequest(c, err)
+		return
+	}
+
+	if err := svc.Fix(params); err != nil {
+		controllers.HandleErrorInternalServerError(c, trace.TraceError(err))
+		return
+	}
+
+	controllers.HandleSuccess(c)
+}
@@ -0,0 +1,106 @@
+package services
+
+import (
+	"github.com/crawlab-team/crawlab-core/controllers"
+	"github.com/crawlab-team/go-trace"
+	"github.com/crawlab-team/plugin-dependency/models"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	mongo2 "go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"golang.org/x/sync/singleflight"
+	"time"
+)
+
+const (
+	cacheTtlSearch = 10 * time.Minute
+	cacheTtlDetail = 1 * time.Hour
+)
+
+// sfGroup collapses concurrent identical cache-miss requests into a
+// single upstream call to npms.io.
+var sfGroup singleflight.Group
+
+// ensureCacheIndex creates the TTL index on expire_at so expired cache
+// entries are reaped by MongoDB itself, rather than accumulating forever.
+// It is safe to call repeatedly: creating an already-existing index is a
+// no-op.
+func (svc *NodeService) ensureCacheIndex() (err error) {
+	_, err = svc.parent.colCache.CreateIndex(mongo2.IndexModel{
+		Keys:    bson.M{"expire_at": 1},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	})
+	return err
+}
+
+// cacheGet returns the cached payload for key, if present and unexpired.
+func (svc *NodeService) cacheGet(key string) (payload []byte, ok bool) {
+	var entry models.NodeRegistryCache
+	filter := bson.M{
+		"key":       key,
+		"expire_at": bson.M{"$gt": nowFunc()},
+	}
+	if err := svc.parent.colCache.Find(filter, nil).One(&entry); err != nil {
+		return nil, false
+	}
+	return entry.Payload, true
+}
+
+// cacheSet upserts the payload for key with the given TTL.
+func (svc *NodeService) cacheSet(key, endpoint string, payload []byte, ttl time.Duration) (err error) {
+	entry := models.NodeRegistryCache{
+		Key:      key,
+		Endpoint: endpoint,
+		Payload:  payload,
+		ExpireAt: nowFunc().Add(ttl),
+	}
+	_, err = svc.parent.colCache.ReplaceOne(bson.M{"key": key}, entry, true)
+	return err
+}
+
+// fetchWithCache returns the cached payload for key if present, otherwise
+// calls fetch (deduping concurrent identical calls via sfGroup), caches
+// the result for ttl, and returns it.
+func (svc *NodeService) fetchWithCache(key, endpoint string, ttl time.Duration, fetch func() ([]byte, error)) (payload []byte, err error) {
+	if data, ok := svc.cacheGet(key); ok {
+		return data, nil
+	}
+
+	v, err, _ := sfGroup.Do(key, func() (interface{}, error) {
+		data, err := fetch()
+		if err != nil {
+			return nil, err
+		}
+		if err := svc.cacheSet(key, endpoint, data, ttl); err != nil {
+			return nil, err
+		}
+		return data, nil
+	})
+	if err != nil {
+		return nil, trace.TraceError(err)
+	}
+	return v.([]byte), nil
+}
+
+// invalidateCache is exposed as POST /node/cache/invalidate for forced
+// refresh; an empty body clears the whole cache.
+func (svc *NodeService) invalidateCache(c *gin.Context) {
+	var payload struct {
+		Key string `json:"key"`
+	}
+	_ = c.ShouldBindJSON(&payload)
+
+	filter := bson.M{}
+	if payload.Key != "" {
+		filter["key"] = payload.Key
+	}
+	if _, err := svc.parent.colCache.DeleteAll(filter); err != nil {
+		controllers.HandleErrorInternalServerError(c, trace.TraceError(err))
+		return
+	}
+
+	controllers.HandleSuccess(c)
+}
+
+// nowFunc is indirected for testability.
+var nowFunc = time.Now
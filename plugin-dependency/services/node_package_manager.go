@@ -0,0 +1,421 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/crawlab-team/plugin-dependency/constants"
+	"github.com/crawlab-team/plugin-dependency/entity"
+	"github.com/crawlab-team/plugin-dependency/models"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// PackageManager abstracts the differences between npm, yarn and pnpm so
+// that NodeService can list/install/uninstall global packages without
+// caring which one is installed on the node.
+type PackageManager interface {
+	// Bin is the executable to invoke, e.g. "npm", "yarn", "pnpm".
+	Bin() string
+
+	// ListArgs returns the arguments used to list globally installed
+	// packages as JSON.
+	ListArgs() []string
+
+	// ParseList parses the stdout of the list command into dependencies.
+	ParseList(data []byte) (deps []models.Dependency, err error)
+
+	// InstallArgs returns the arguments used to install the given
+	// dependencies globally.
+	InstallArgs(params entity.InstallParams) (args []string)
+
+	// UninstallArgs returns the arguments used to uninstall the given
+	// dependencies globally.
+	UninstallArgs(params entity.UninstallParams) (args []string)
+
+	// AuditArgs returns the arguments used to audit the global install for
+	// known vulnerabilities as JSON.
+	AuditArgs() []string
+
+	// ParseAudit parses the stdout of the audit command into
+	// vulnerabilities.
+	ParseAudit(data []byte) (vulns []models.Vulnerability, err error)
+
+	// PrepareAudit sets up whatever the audit command needs to run
+	// against the global install (e.g. npm requires a project with a
+	// lockfile, which it otherwise has no notion of for global packages).
+	// It returns the directory the audit command should run in ("" for
+	// the current directory) and a cleanup func to run afterwards.
+	PrepareAudit(deps []models.Dependency) (dir string, cleanup func(), err error)
+}
+
+// getPackageManager resolves a PackageManager from params.Cmd, falling
+// back to npm when it is empty for backwards compatibility.
+func getPackageManager(cmd string) (pm PackageManager, err error) {
+	switch cmd {
+	case "", "npm":
+		return &npmPackageManager{}, nil
+	case "yarn":
+		return &yarnPackageManager{}, nil
+	case "pnpm":
+		return &pnpmPackageManager{}, nil
+	default:
+		return nil, fmt.Errorf("unsupported node package manager: %s", cmd)
+	}
+}
+
+// npmPackageManager
+
+type npmPackageManager struct{}
+
+func (pm *npmPackageManager) Bin() string {
+	return "npm"
+}
+
+func (pm *npmPackageManager) ListArgs() []string {
+	return []string{"list", "-g", "--json", "--depth", "0"}
+}
+
+func (pm *npmPackageManager) ParseList(data []byte) (deps []models.Dependency, err error) {
+	var res entity.NpmListResult
+	if err := json.Unmarshal(data, &res); err != nil {
+		return nil, err
+	}
+	for name, p := range res.Dependencies {
+		d := models.Dependency{
+			Name:    name,
+			Version: p.Version,
+		}
+		d.Type = constants.DependencyTypeNode
+		deps = append(deps, d)
+	}
+	return deps, nil
+}
+
+func (pm *npmPackageManager) InstallArgs(params entity.InstallParams) (args []string) {
+	args = append(args, "install", "-g")
+	if params.Proxy != "" {
+		args = append(args, "--registry", params.Proxy)
+	}
+	if !params.UseConfig {
+		for _, depName := range params.Names {
+			if params.Upgrade {
+				depName = depName + "@latest"
+			}
+			args = append(args, depName)
+		}
+	}
+	return args
+}
+
+func (pm *npmPackageManager) UninstallArgs(params entity.UninstallParams) (args []string) {
+	args = append(args, "uninstall", "-g")
+	args = append(args, params.Names...)
+	return args
+}
+
+func (pm *npmPackageManager) AuditArgs() []string {
+	return []string{"audit", "--json"}
+}
+
+func (pm *npmPackageManager) ParseAudit(data []byte) (vulns []models.Vulnerability, err error) {
+	return parseNpmStyleAudit(data)
+}
+
+// PrepareAudit builds a throwaway project declaring the globally installed
+// packages as dependencies and generates its lockfile, since `npm audit`
+// has no global mode and refuses to run without a lockfile in its cwd.
+func (pm *npmPackageManager) PrepareAudit(deps []models.Dependency) (dir string, cleanup func(), err error) {
+	dir, err = ioutil.TempDir("", "crawlab-npm-audit-*")
+	if err != nil {
+		return "", nil, err
+	}
+	cleanup = func() { _ = os.RemoveAll(dir) }
+
+	dependencies := map[string]string{}
+	for _, d := range deps {
+		version := d.Version
+		if version == "" {
+			version = "*"
+		}
+		dependencies[d.Name] = version
+	}
+
+	pkgJson, err := json.Marshal(struct {
+		Name         string            `json:"name"`
+		Version      string            `json:"version"`
+		Dependencies map[string]string `json:"dependencies"`
+	}{
+		Name:         "crawlab-global-audit",
+		Version:      "0.0.0",
+		Dependencies: dependencies,
+	})
+	if err != nil {
+		cleanup()
+		return "", nil, err
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "package.json"), pkgJson, 0644); err != nil {
+		cleanup()
+		return "", nil, err
+	}
+
+	lockCmd := exec.Command(pm.Bin(), "install", "--package-lock-only", "--ignore-scripts")
+	lockCmd.Dir = dir
+	if err := lockCmd.Run(); err != nil {
+		cleanup()
+		return "", nil, err
+	}
+
+	return dir, cleanup, nil
+}
+
+// yarnPackageManager
+
+type yarnPackageManager struct{}
+
+func (pm *yarnPackageManager) Bin() string {
+	return "yarn"
+}
+
+func (pm *yarnPackageManager) ListArgs() []string {
+	return []string{"global", "list", "--json"}
+}
+
+func (pm *yarnPackageManager) ParseList(data []byte) (deps []models.Dependency, err error) {
+	// yarn prints one JSON object per line; we only care about the line
+	// carrying the dependency tree.
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		if line == "" {
+			continue
+		}
+		var res entity.YarnListResult
+		if err := json.Unmarshal([]byte(line), &res); err != nil {
+			continue
+		}
+		if res.Type != "tree" {
+			continue
+		}
+		for _, t := range res.Data.Trees {
+			name, version := splitYarnTreeName(t.Name)
+			d := models.Dependency{
+				Name:    name,
+				Version: version,
+			}
+			d.Type = constants.DependencyTypeNode
+			deps = append(deps, d)
+		}
+	}
+	return deps, nil
+}
+
+func splitYarnTreeName(name string) (pkgName string, version string) {
+	idx := strings.LastIndex(name, "@")
+	if idx <= 0 {
+		return name, ""
+	}
+	return name[:idx], name[idx+1:]
+}
+
+func (pm *yarnPackageManager) InstallArgs(params entity.InstallParams) (args []string) {
+	args = append(args, "global", "add")
+	if params.Proxy != "" {
+		args = append(args, "--registry", params.Proxy)
+	}
+	if !params.UseConfig {
+		for _, depName := range params.Names {
+			if params.Upgrade {
+				depName = depName + "@latest"
+			}
+			args = append(args, depName)
+		}
+	}
+	return args
+}
+
+func (pm *yarnPackageManager) UninstallArgs(params entity.UninstallParams) (args []string) {
+	args = append(args, "global", "remove")
+	args = append(args, params.Names...)
+	return args
+}
+
+func (pm *yarnPackageManager) AuditArgs() []string {
+	return []string{"audit", "--json"}
+}
+
+func (pm *yarnPackageManager) ParseAudit(data []byte) (vulns []models.Vulnerability, err error) {
+	// yarn prints one JSON object per line; advisories come as
+	// type "auditAdvisory" entries wrapping the same advisory shape npm
+	// uses.
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		if line == "" {
+			continue
+		}
+		var entry struct {
+			Type string `json:"type"`
+			Data struct {
+				Advisory entity.NpmAuditAdvisory `json:"advisory"`
+			} `json:"data"`
+		}
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			continue
+		}
+		if entry.Type != "auditAdvisory" {
+			continue
+		}
+		vulns = append(vulns, vulnerabilityFromAdvisory(entry.Data.Advisory))
+	}
+	return vulns, nil
+}
+
+// PrepareAudit points `yarn audit` at the yarn global directory (as printed
+// by `yarn global dir`), which holds its own yarn.lock; nothing needs
+// cleaning up afterwards.
+func (pm *yarnPackageManager) PrepareAudit(deps []models.Dependency) (dir string, cleanup func(), err error) {
+	out, err := exec.Command(pm.Bin(), "global", "dir").Output()
+	if err != nil {
+		return "", nil, err
+	}
+	return strings.TrimSpace(string(out)), func() {}, nil
+}
+
+// pnpmPackageManager
+
+type pnpmPackageManager struct{}
+
+func (pm *pnpmPackageManager) Bin() string {
+	return "pnpm"
+}
+
+func (pm *pnpmPackageManager) ListArgs() []string {
+	return []string{"list", "-g", "--json"}
+}
+
+func (pm *pnpmPackageManager) ParseList(data []byte) (deps []models.Dependency, err error) {
+	var res entity.PnpmListResult
+	if err := json.Unmarshal(data, &res); err != nil {
+		return nil, err
+	}
+	for _, item := range res {
+		for name, p := range item.Dependencies {
+			d := models.Dependency{
+				Name:    name,
+				Version: p.Version,
+			}
+			d.Type = constants.DependencyTypeNode
+			deps = append(deps, d)
+		}
+	}
+	return deps, nil
+}
+
+func (pm *pnpmPackageManager) InstallArgs(params entity.InstallParams) (args []string) {
+	args = append(args, "add", "-g")
+	if params.Proxy != "" {
+		args = append(args, "--registry", params.Proxy)
+	}
+	if !params.UseConfig {
+		for _, depName := range params.Names {
+			if params.Upgrade {
+				depName = depName + "@latest"
+			}
+			args = append(args, depName)
+		}
+	}
+	return args
+}
+
+func (pm *pnpmPackageManager) UninstallArgs(params entity.UninstallParams) (args []string) {
+	args = append(args, "remove", "-g")
+	args = append(args, params.Names...)
+	return args
+}
+
+func (pm *pnpmPackageManager) AuditArgs() []string {
+	return []string{"audit", "--json"}
+}
+
+func (pm *pnpmPackageManager) ParseAudit(data []byte) (vulns []models.Vulnerability, err error) {
+	return parseNpmStyleAudit(data)
+}
+
+// PrepareAudit points `pnpm audit` at the pnpm global directory (the parent
+// of the `pnpm root -g` node_modules), which holds its own pnpm-lock.yaml;
+// nothing needs cleaning up afterwards.
+func (pm *pnpmPackageManager) PrepareAudit(deps []models.Dependency) (dir string, cleanup func(), err error) {
+	out, err := exec.Command(pm.Bin(), "root", "-g").Output()
+	if err != nil {
+		return "", nil, err
+	}
+	return filepath.Dir(strings.TrimSpace(string(out))), func() {}, nil
+}
+
+// parseNpmStyleAudit parses the advisory report shared by `npm audit
+// --json` and `pnpm audit --json`. It supports both the npm <7
+// "advisories" map and the npm >=7 "vulnerabilities" map (auditReportVersion
+// 2); an unrecognized/future schema yields zero vulnerabilities rather
+// than erroring, so callers should not treat an empty result as proof the
+// install is clean.
+func parseNpmStyleAudit(data []byte) (vulns []models.Vulnerability, err error) {
+	var res entity.NpmAuditResult
+	if err := json.Unmarshal(data, &res); err != nil {
+		return nil, err
+	}
+	for _, advisory := range res.Advisories {
+		vulns = append(vulns, vulnerabilityFromAdvisory(advisory))
+	}
+	for _, vuln := range res.Vulnerabilities {
+		vulns = append(vulns, vulnerabilityFromV2(vuln))
+	}
+	return vulns, nil
+}
+
+func vulnerabilityFromAdvisory(advisory entity.NpmAuditAdvisory) models.Vulnerability {
+	version := ""
+	if len(advisory.Findings) > 0 {
+		version = advisory.Findings[0].Version
+	}
+	return models.Vulnerability{
+		Package:          advisory.ModuleName,
+		InstalledVersion: version,
+		Severity:         advisory.Severity,
+		AdvisoryUrl:      advisory.Url,
+		PatchedRange:     advisory.PatchedVersions,
+		FixAvailable:     advisory.PatchedVersions != "" && advisory.PatchedVersions != "<0.0.0",
+	}
+}
+
+func vulnerabilityFromV2(vuln entity.NpmAuditVulnerability) models.Vulnerability {
+	var advisoryUrl string
+	for _, raw := range vuln.Via {
+		var via entity.NpmAuditVulnerabilityVia
+		if err := json.Unmarshal(raw, &via); err == nil && via.Url != "" {
+			advisoryUrl = via.Url
+			break
+		}
+	}
+
+	// vuln.Range is the *vulnerable* range, not the patched one — only a
+	// fixAvailable object tells us an actual patched version.
+	patchedRange := ""
+	fixAvailable := false
+	var fix bool
+	if err := json.Unmarshal(vuln.FixAvailable, &fix); err == nil {
+		fixAvailable = fix
+	} else {
+		var fixObj entity.NpmAuditFixAvailable
+		if err := json.Unmarshal(vuln.FixAvailable, &fixObj); err == nil && fixObj.Version != "" {
+			fixAvailable = true
+			patchedRange = fixObj.Version
+		}
+	}
+
+	return models.Vulnerability{
+		Package:      vuln.Name,
+		Severity:     vuln.Severity,
+		AdvisoryUrl:  advisoryUrl,
+		PatchedRange: patchedRange,
+		FixAvailable: fixAvailable,
+	}
+}
@@ -0,0 +1,193 @@
+package services
+
+import (
+	"encoding/base64"
+	"fmt"
+	"github.com/crawlab-team/go-trace"
+	"github.com/crawlab-team/plugin-dependency/entity"
+	"github.com/crawlab-team/plugin-dependency/models"
+	"github.com/imroc/req"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// configureRegistryAuth writes a temp .npmrc for the configured
+// registries (if any) and points cmd at it via NPM_CONFIG_USERCONFIG, so
+// install/uninstall commands authenticate without the token ever
+// appearing on the command line. The returned cleanup func must be
+// called once the command has finished.
+func (svc *NodeService) configureRegistryAuth(cmd *exec.Cmd) (cleanup func(), err error) {
+	registries, err := svc.getRegistries()
+	if err != nil {
+		return func() {}, err
+	}
+
+	path, cleanup, err := writeNpmrc(registries)
+	if err != nil {
+		return func() {}, err
+	}
+	if path == "" {
+		return cleanup, nil
+	}
+
+	cmd.Env = append(os.Environ(), "NPM_CONFIG_USERCONFIG="+path)
+	return cleanup, nil
+}
+
+// getRegistries returns the configured node registries, persisted
+// alongside the rest of the plugin config.
+func (svc *NodeService) getRegistries() (registries []models.NodeRegistry, err error) {
+	if err := svc.parent.colRegistry.Find(nil, nil).All(&registries); err != nil {
+		return nil, err
+	}
+	return registries, nil
+}
+
+// matchRegistryByScope returns the registry configured for the scope of
+// pkgName (e.g. "@mycorp/foo" matches the registry with Scope "@mycorp"),
+// if any.
+func (svc *NodeService) matchRegistryByScope(pkgName string) (registry *models.NodeRegistry, ok bool) {
+	if !strings.HasPrefix(pkgName, "@") || !strings.Contains(pkgName, "/") {
+		return nil, false
+	}
+	scope := pkgName[:strings.Index(pkgName, "/")]
+
+	registries, err := svc.getRegistries()
+	if err != nil {
+		return nil, false
+	}
+	for _, r := range registries {
+		if r.Scope == scope {
+			return &r, true
+		}
+	}
+	return nil, false
+}
+
+// registryClient queries a configured registry's REST endpoints for
+// search and package detail, attaching auth as configured.
+type registryClient struct {
+	registry models.NodeRegistry
+}
+
+func newRegistryClient(registry models.NodeRegistry) *registryClient {
+	return &registryClient{registry: registry}
+}
+
+func (rc *registryClient) authHeader() req.Header {
+	switch rc.registry.AuthType {
+	case "bearer":
+		return req.Header{"Authorization": "Bearer " + rc.registry.Token}
+	case "basic":
+		return req.Header{"Authorization": "Basic " + base64.StdEncoding.EncodeToString([]byte(rc.registry.Token))}
+	default:
+		return req.Header{}
+	}
+}
+
+func (rc *registryClient) search(query string, from, size int) (res entity.NpmRegistrySearchResult, err error) {
+	reqSession := req.New()
+	reqSession.SetTimeout(15 * time.Second)
+
+	requestUrl := fmt.Sprintf("%s/-/v1/search?from=%d&text=%s&size=%d", strings.TrimSuffix(rc.registry.Url, "/"), from, url.QueryEscape(query), size)
+	r, err := reqSession.Get(requestUrl, rc.authHeader())
+	if err != nil {
+		return res, trace.TraceError(err)
+	}
+	if err := r.ToJSON(&res); err != nil {
+		return res, trace.TraceError(err)
+	}
+	return res, nil
+}
+
+func (rc *registryClient) getPackage(name string) (res entity.NpmRegistryPackageResult, err error) {
+	reqSession := req.New()
+	reqSession.SetTimeout(15 * time.Second)
+
+	requestUrl := fmt.Sprintf("%s/%s", strings.TrimSuffix(rc.registry.Url, "/"), encodePackageNameForRegistry(name))
+	r, err := reqSession.Get(requestUrl, rc.authHeader())
+	if err != nil {
+		return res, trace.TraceError(err)
+	}
+	if err := r.ToJSON(&res); err != nil {
+		return res, trace.TraceError(err)
+	}
+	return res, nil
+}
+
+// encodePackageNameForRegistry encodes a package name for use as the path
+// segment of a registry's package document endpoint. Scoped packages must
+// have their separating slash percent-encoded (e.g. "@scope%2Fname"); the
+// leading "@" is left as-is, matching what npm registries expect.
+func encodePackageNameForRegistry(name string) string {
+	if !strings.HasPrefix(name, "@") || !strings.Contains(name, "/") {
+		return url.QueryEscape(name)
+	}
+	idx := strings.Index(name, "/")
+	scope, pkg := name[:idx], name[idx+1:]
+	return "@" + url.QueryEscape(strings.TrimPrefix(scope, "@")) + "%2F" + url.QueryEscape(pkg)
+}
+
+// searchRegistry queries the given registry's search endpoint and adapts
+// the result into the same shape GetRepoList builds from npms.io.
+func (svc *NodeService) searchRegistry(registry models.NodeRegistry, query string, from, size int) (deps []models.Dependency, depNames []string, total int, err error) {
+	res, err := newRegistryClient(registry).search(query, from, size)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	for _, o := range res.Objects {
+		d := models.Dependency{
+			Name:          o.Package.Name,
+			LatestVersion: o.Package.Version,
+		}
+		deps = append(deps, d)
+		depNames = append(depNames, d.Name)
+	}
+	return deps, depNames, res.Total, nil
+}
+
+// writeNpmrc writes a temporary .npmrc-style config file carrying the
+// registry and auth settings for the given registries, so install/
+// uninstall commands authenticate without ever putting tokens on the
+// command line (and therefore never in command logs).
+func writeNpmrc(registries []models.NodeRegistry) (path string, cleanup func(), err error) {
+	if len(registries) == 0 {
+		return "", func() {}, nil
+	}
+
+	f, err := ioutil.TempFile("", "crawlab-npmrc-*")
+	if err != nil {
+		return "", nil, trace.TraceError(err)
+	}
+	defer f.Close()
+
+	var lines []string
+	for _, r := range registries {
+		host := strings.TrimPrefix(strings.TrimPrefix(r.Url, "https://"), "http://")
+		if r.Scope != "" {
+			lines = append(lines, fmt.Sprintf("%s:registry=%s", r.Scope, r.Url))
+		} else {
+			lines = append(lines, fmt.Sprintf("registry=%s", r.Url))
+		}
+		switch r.AuthType {
+		case "bearer":
+			lines = append(lines, fmt.Sprintf("//%s/:_authToken=%s", host, r.Token))
+		case "basic":
+			lines = append(lines, fmt.Sprintf("//%s/:_auth=%s", host, base64.StdEncoding.EncodeToString([]byte(r.Token))))
+		}
+	}
+
+	if _, err := f.WriteString(strings.Join(lines, "\n") + "\n"); err != nil {
+		return "", nil, trace.TraceError(err)
+	}
+
+	path = f.Name()
+	cleanup = func() {
+		_ = os.Remove(path)
+	}
+	return path, cleanup, nil
+}